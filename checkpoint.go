@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// checkpoint is the last position seen for one stream, written to the
+// --checkpoint-file after every event (or stitched multiline event) that
+// tailer emits, and again with NextForwardToken at each page boundary. On
+// restart a checkpoint that carries a NextForwardToken resumes from that
+// token alone; one without a token resumes from Timestamp via StartTime and
+// drops the events up to and including EventID (see
+// streamTailer.shouldSkipForResume). A stored token that has expired is
+// not replaced by the Timestamp/EventID resume: the tailer keeps retrying
+// the token.
+type checkpoint struct {
+	LogGroupName     string `json:"logGroupName"`
+	LogStreamName    string `json:"logStreamName"`
+	Timestamp        int64  `json:"timestamp"`
+	EventID          string `json:"eventId,omitempty"`
+	NextForwardToken string `json:"nextForwardToken,omitempty"`
+}
+
+// checkpointStore persists checkpoints to a single JSON file, keyed by
+// stream, so a restarted process can resume each stream from where it left
+// off instead of double-printing or losing events across a crash/restart.
+type checkpointStore struct {
+	path string
+
+	mu   sync.Mutex
+	data map[string]checkpoint
+}
+
+func loadCheckpointStore(path string) (*checkpointStore, error) {
+	s := &checkpointStore{path: path, data: make(map[string]checkpoint)}
+	if path == "" {
+		return s, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []checkpoint
+	if err := json.Unmarshal(b, &checkpoints); err != nil {
+		return nil, err
+	}
+	for _, cp := range checkpoints {
+		s.data[tailerKey(cp.LogGroupName, cp.LogStreamName)] = cp
+	}
+	return s, nil
+}
+
+func (s *checkpointStore) get(logGroupName, logStreamName string) (checkpoint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp, ok := s.data[tailerKey(logGroupName, logStreamName)]
+	return cp, ok
+}
+
+// save records cp and rewrites the checkpoint file. Writing the whole file
+// on every event keeps the format simple (a plain JSON array you can
+// inspect or hand-edit) at the cost of an extra write per event; CloudWatch
+// tailing is not so high-volume that this becomes a bottleneck.
+func (s *checkpointStore) save(cp checkpoint) error {
+	if s.path == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[tailerKey(cp.LogGroupName, cp.LogStreamName)] = cp
+
+	checkpoints := make([]checkpoint, 0, len(s.data))
+	for _, c := range s.data {
+		checkpoints = append(checkpoints, c)
+	}
+
+	b, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// fileConfig is the shape of --config: a YAML file that SIGHUP reloads
+// without restarting the process.
+type fileConfig struct {
+	LogGroupName  []string `yaml:"log_group_name"`
+	LogStreamLike string   `yaml:"log_stream_like"`
+	FilterPattern string   `yaml:"filter_pattern"`
+	Output        string   `yaml:"output"`
+}
+
+func loadFileConfig(path string) (fileConfig, error) {
+	var fc fileConfig
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fc, err
+	}
+	if err := yaml.Unmarshal(b, &fc); err != nil {
+		return fc, err
+	}
+	return fc, nil
+}
+
+// runtimeConfig holds the subset of flags that --config/SIGHUP can change
+// while the process is running: log group names, the stream-matching
+// pattern, the filter pattern, and the output format. Everything else
+// (credentials, polling cadence, checkpoint location, ...) is fixed for
+// the life of the process. A reload re-runs log-group and stream discovery,
+// which starts tailers for newly matching streams; tailers already running
+// for streams that no longer match keep running until they go idle.
+type runtimeConfig struct {
+	mu sync.RWMutex
+
+	logGroupNames []string
+	logStreamLike string
+	streamMatcher *regexp.Regexp
+	filterPattern string
+	output        string
+}
+
+func newRuntimeConfig(logGroupNames []string, logStreamLike, filterPattern, output string) (*runtimeConfig, error) {
+	rc := &runtimeConfig{}
+	if err := rc.apply(logGroupNames, logStreamLike, filterPattern, output); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+func (rc *runtimeConfig) apply(logGroupNames []string, logStreamLike, filterPattern, output string) error {
+	matcher, err := regexp.Compile(strings.Replace(logStreamLike, "*", ".*", -1))
+	if err != nil {
+		return err
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.logGroupNames = logGroupNames
+	rc.logStreamLike = logStreamLike
+	rc.streamMatcher = matcher
+	rc.filterPattern = filterPattern
+	rc.output = output
+	return nil
+}
+
+// reload re-reads path and, if it parses cleanly, swaps in the new values.
+// A malformed config is logged and otherwise ignored so a typo in the file
+// can't take down a running tail.
+func (rc *runtimeConfig) reload(path string) error {
+	fc, err := loadFileConfig(path)
+	if err != nil {
+		return err
+	}
+	logGroupNames := fc.LogGroupName
+	if len(logGroupNames) == 0 {
+		logGroupNames = rc.LogGroupNames()
+	}
+	logStreamLike := fc.LogStreamLike
+	if logStreamLike == "" {
+		logStreamLike = rc.LogStreamLike()
+	}
+	output := fc.Output
+	if output == "" {
+		output = rc.Output()
+	}
+	filterPattern := fc.FilterPattern
+	if filterPattern == "" {
+		filterPattern = rc.FilterPattern()
+	}
+	return rc.apply(logGroupNames, logStreamLike, filterPattern, output)
+}
+
+func (rc *runtimeConfig) LogGroupNames() []string {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.logGroupNames
+}
+
+func (rc *runtimeConfig) LogStreamLike() string {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.logStreamLike
+}
+
+func (rc *runtimeConfig) StreamMatcher() *regexp.Regexp {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.streamMatcher
+}
+
+func (rc *runtimeConfig) FilterPattern() string {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.filterPattern
+}
+
+func (rc *runtimeConfig) Output() string {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.output
+}
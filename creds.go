@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go-v2/aws/ec2rolecreds"
+	"github.com/aws/aws-sdk-go-v2/aws/endpointcreds"
+	"github.com/aws/aws-sdk-go-v2/aws/external"
+	"github.com/aws/aws-sdk-go-v2/aws/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// ecsContainerCredentialsRelativeURIEnv is set by the ECS agent on tasks
+// that have a task role; its presence is how the AWS CLI/SDKs decide to
+// use the ECS container credentials endpoint instead of EC2 IMDS.
+const ecsContainerCredentialsRelativeURIEnv = "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"
+
+// ecsContainerCredentialsEndpoint is the fixed link-local address the ECS
+// agent serves task role credentials from; relative URI is read from
+// ecsContainerCredentialsRelativeURIEnv.
+const ecsContainerCredentialsEndpoint = "http://169.254.170.2"
+
+// fallbackCredentialsProvider returns an explicit EC2 instance role or ECS
+// task role provider, preferring ECS when its environment variable is
+// present. It is only consulted when the shared profile/environment chain
+// comes up empty, so a host with neither still fails with a clear error
+// instead of silently hanging off IMDS.
+func fallbackCredentialsProvider(cfg aws.Config) aws.CredentialsProvider {
+	if relativeURI := os.Getenv(ecsContainerCredentialsRelativeURIEnv); relativeURI != "" {
+		return endpointcreds.New(ecsContainerCredentialsEndpoint + relativeURI)
+	}
+	return ec2rolecreds.New(ec2metadata.New(cfg))
+}
+
+// loadAWSConfig builds the aws.Config used for every CloudWatch Logs call.
+// external.LoadDefaultAWSConfig's default chain covers --profile and
+// environment-variable credentials; if that chain can't produce usable
+// credentials, loadAWSConfig explicitly falls back to the EC2 instance
+// metadata service or the ECS container credentials endpoint rather than
+// trusting that the default chain's own fallback does the same. When
+// --assume-role-arn is set, the resulting credentials are wrapped in an
+// stscreds.AssumeRoleProvider so every downstream API call transparently
+// refreshes as the assumed role's temporary session nears expiry.
+func loadAWSConfig(profile, region, endpointURL, assumeRoleArn, roleSessionName, externalID string) (aws.Config, error) {
+	stscreds.DefaultDuration = time.Minute * 60
+
+	cfg, err := external.LoadDefaultAWSConfig(
+		external.WithMFATokenFunc(stscreds.StdinTokenProvider),
+		external.WithSharedConfigProfile(profile),
+		external.WithRegion(region),
+	)
+	if err != nil {
+		return cfg, err
+	}
+
+	if cfg.Credentials == nil {
+		cfg.Credentials = fallbackCredentialsProvider(cfg)
+	} else if _, retrieveErr := cfg.Credentials.Retrieve(); retrieveErr != nil {
+		cfg.Credentials = fallbackCredentialsProvider(cfg)
+	}
+
+	if endpointURL != "" {
+		cfg.EndpointResolver = aws.ResolveWithEndpointURL(endpointURL)
+	}
+
+	if assumeRoleArn != "" {
+		stsClient := sts.New(cfg)
+		provider := stscreds.NewAssumeRoleProvider(stsClient, assumeRoleArn)
+		if roleSessionName != "" {
+			provider.RoleSessionName = roleSessionName
+		}
+		if externalID != "" {
+			provider.ExternalID = &externalID
+		}
+		cfg.Credentials = provider
+	}
+
+	return cfg, nil
+}
+
+func checkCredentials(cfg aws.Config) error {
+	var err error
+	if cfg.Credentials != nil {
+		_, err = cfg.Credentials.Retrieve()
+	}
+	return err
+}
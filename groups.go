@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+)
+
+// stringSliceFlag backs a repeatable flag, e.g. multiple --log-group-name
+// occurrences.
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+var _ flag.Value = (*stringSliceFlag)(nil)
+
+// globToPrefixAndMatcher turns a "*"-glob like --log-group-like into the
+// literal prefix up to its first "*" (used to narrow the DescribeLogGroups
+// pagination) and a regexp matching the full glob.
+func globToPrefixAndMatcher(glob string) (string, *regexp.Regexp) {
+	prefix := glob[:strings.IndexAny(glob+"*", "*")]
+	matcher := regexp.MustCompile("^" + strings.Replace(regexp.QuoteMeta(glob), `\*`, ".*", -1) + "$")
+	return prefix, matcher
+}
+
+// discoverLogGroups returns the union of explicitly named log groups and
+// any group whose whole name matches logGroupLike (a "*"-glob; every other
+// character is literal). Matching uses DescribeLogGroups paginated by the
+// glob's literal prefix, so a pattern like "/aws/lambda/myapp-*" only has
+// to page through that lambda's groups rather than the whole account.
+func discoverLogGroups(logger *log.Logger, logsClient *cloudwatchlogs.CloudWatchLogs, explicitGroups []string, logGroupLike string) ([]string, error) {
+	seen := map[string]bool{}
+	var groups []string
+	for _, name := range explicitGroups {
+		if !seen[name] {
+			seen[name] = true
+			groups = append(groups, name)
+		}
+	}
+
+	if logGroupLike == "" {
+		return groups, nil
+	}
+
+	prefix, matcher := globToPrefixAndMatcher(logGroupLike)
+
+	describeLogGroupsInput := cloudwatchlogs.DescribeLogGroupsInput{LogGroupNamePrefix: &prefix}
+	describeLogGroupsRequest := logsClient.DescribeLogGroupsRequest(&describeLogGroupsInput)
+	describeLogGroupsRequestIter := describeLogGroupsRequest.Paginate()
+
+	for describeLogGroupsRequestIter.Next() {
+		fmt.Fprint(os.Stderr, ".")
+		for _, item := range describeLogGroupsRequestIter.CurrentPage().LogGroups {
+			if matcher.MatchString(*item.LogGroupName) && !seen[*item.LogGroupName] {
+				seen[*item.LogGroupName] = true
+				groups = append(groups, *item.LogGroupName)
+			}
+		}
+	}
+	if err := describeLogGroupsRequestIter.Err(); err != nil {
+		logger.Printf("Error getting log groups: %s", err.Error())
+		return groups, err
+	}
+
+	return groups, nil
+}
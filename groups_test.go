@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestGlobToPrefixAndMatcher(t *testing.T) {
+	cases := []struct {
+		glob       string
+		wantPrefix string
+		matches    []string
+		nonMatches []string
+	}{
+		{
+			glob:       "/aws/lambda/myapp-*",
+			wantPrefix: "/aws/lambda/myapp-",
+			matches:    []string{"/aws/lambda/myapp-prod", "/aws/lambda/myapp-"},
+			nonMatches: []string{"/aws/lambda/otherapp-prod", "/aws/lambda/myapp"},
+		},
+		{
+			glob:       "*",
+			wantPrefix: "",
+			matches:    []string{"anything", ""},
+		},
+		{
+			glob:       "/aws/lambda/exact",
+			wantPrefix: "/aws/lambda/exact",
+			matches:    []string{"/aws/lambda/exact"},
+			nonMatches: []string{"/aws/lambda/exact2"},
+		},
+	}
+
+	for _, c := range cases {
+		prefix, matcher := globToPrefixAndMatcher(c.glob)
+		if prefix != c.wantPrefix {
+			t.Errorf("globToPrefixAndMatcher(%q) prefix = %q, want %q", c.glob, prefix, c.wantPrefix)
+		}
+		for _, m := range c.matches {
+			if !matcher.MatchString(m) {
+				t.Errorf("globToPrefixAndMatcher(%q) matcher did not match %q", c.glob, m)
+			}
+		}
+		for _, nm := range c.nonMatches {
+			if matcher.MatchString(nm) {
+				t.Errorf("globToPrefixAndMatcher(%q) matcher unexpectedly matched %q", c.glob, nm)
+			}
+		}
+	}
+}
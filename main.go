@@ -1,31 +1,21 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"regexp"
-	"sort"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/aws/external"
-	"github.com/aws/aws-sdk-go-v2/aws/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 )
 
-func checkCredentials(cfg aws.Config) error {
-	var err error
-	if cfg.Credentials != nil {
-		_, err = cfg.Credentials.Retrieve()
-	}
-	return err
-}
-
 func getLogStreams(logger *log.Logger, logsClient *cloudwatchlogs.CloudWatchLogs, logGroupName string, logStreamMatcher *regexp.Regexp, initialStartTime int64) ([]string, error) {
 	var logStreamNames []string
 	logStreamMinTimestamp := initialStartTime - int64(3*60*60*1000) // 3 hour
@@ -42,7 +32,7 @@ loop:
 	for {
 		found := false
 		for describeLogStreamsRequestIter.Next() {
-			fmt.Printf(".")
+			fmt.Fprint(os.Stderr, ".")
 			for _, item := range describeLogStreamsRequestIter.CurrentPage().LogStreams {
 				if logStreamMinTimestamp <= *item.LastEventTimestamp {
 					logStreamMatches := logStreamMatcher.FindAllStringIndex(*item.LogStreamName, -1)
@@ -72,60 +62,72 @@ loop:
 	return logStreamNames, nil
 }
 
-type msg []cloudwatchlogs.FilteredLogEvent
-
-func (m msg) Len() int           { return len(m) }
-func (m msg) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
-func (m msg) Less(i, j int) bool { return *m[i].IngestionTime < *m[j].IngestionTime }
-
 func main() {
 
 	var logger = log.New(os.Stderr, "", 0)
 
 	profileInput := flag.String("profile", "", "An AWS credential profile (refer to https://docs.aws.amazon.com/cli/latest/userguide/cli-multiple-profiles.html)")
 	regionInput := flag.String("region", "us-east-1", "The AWS region associated with the target log group")
-	logGroupNameInput := flag.String("log-group-name", "", "An AWS log group that may or may not exist at runtime (polling will continue to occur)")
+	var logGroupNameInput stringSliceFlag
+	flag.Var(&logGroupNameInput, "log-group-name", "An AWS log group that may or may not exist at runtime (polling will continue to occur); may be repeated")
+	logGroupLikeInput := flag.String("log-group-like", "", "Also tail any log group whose name matches this \"*\"-glob (e.g. /aws/lambda/myapp-*)")
 
 	logStreamLikeInput := flag.String("log-stream-like", "*", "Target log stream names that match this expression")
 	logStreamRefreshInput := flag.Bool("log-stream-refresh", false, "Perform refreshes of target log streams")
-	filterPatternInput := flag.String("filter-pattern", "", "A valid CloudWatch log filter (refer to https://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/FilterAndPatternSyntax.html)")
+	filterPatternInput := flag.String("filter-pattern", "", "Only print events whose message contains this literal substring (not CloudWatch filter pattern syntax)")
 
 	startTimeInput := flag.String("start-time", time.Now().UTC().Format("2006-01-02T15:04:05Z"), "Events that occurred after this time are returned")
 	endTimeInput := flag.String("end-time", "", "Events that occurred at or before this time are returned")
 
+	maxConcurrentStreamsInput := flag.Int("max-concurrent-streams", 50, "Maximum number of log streams tailed concurrently")
+	pollIntervalInput := flag.Duration("poll-interval", 5*time.Second, "How often each stream tailer polls GetLogEvents for new data")
+	streamInactiveTimeoutInput := flag.Duration("stream-inactive-timeout", 15*time.Minute, "Tear down a stream's tailer once it has gone this long without a new event")
+
+	multilinePatternInput := flag.String("multiline-pattern", "", "Regex matching continuation lines that should be stitched onto the previous event")
+	datetimeFormatInput := flag.String("datetime-format", "", "strftime-style layout (e.g. %Y-%m-%d %H:%M:%S) identifying the start of a new event's message")
+
+	outputInput := flag.String("output", outputText, "Output format: text|json|logfmt|template")
+	templateInput := flag.String("template", "", "Go text/template executed against each event when --output=template")
+	colorInput := flag.String("color", colorAuto, "Colorize output: auto|always|never")
+
+	assumeRoleArnInput := flag.String("assume-role-arn", "", "Assume this role before making any CloudWatch Logs calls")
+	roleSessionNameInput := flag.String("role-session-name", "", "Session name used when --assume-role-arn is set")
+	externalIDInput := flag.String("external-id", "", "External ID used when --assume-role-arn is set")
+	endpointURLInput := flag.String("endpoint-url", "", "Override the CloudWatch Logs / STS endpoint (e.g. for localstack)")
+
+	configInput := flag.String("config", "", "Optional YAML file (log_group_name, log_stream_like, filter_pattern, output) reloaded on SIGHUP")
+	checkpointFileInput := flag.String("checkpoint-file", "", "Persist and resume per-stream tail position across restarts")
+
 	helpInput := flag.Bool("help", false, "Show usage message")
 	showInput := flag.Bool("show", false, "Show input data")
 	flag.Parse()
 
 	if *showInput {
-		logger.Printf("Profile: %s\tRegion: %s\tLog Group: %s\n", *profileInput, *regionInput, *logGroupNameInput)
+		logger.Printf("Profile: %s\tRegion: %s\tLog Groups: %s\tLog Group Like: %s\n", *profileInput, *regionInput, logGroupNameInput.String(), *logGroupLikeInput)
 		logger.Printf("Stream Like: %s\tPattern: %s\n", *logStreamLikeInput, *filterPatternInput)
 		logger.Printf("Start: [%s], End: [%s]\n", *startTimeInput, *endTimeInput)
 	}
 
-	if *logGroupNameInput == "" || *logStreamLikeInput == "" || *helpInput {
+	if (len(logGroupNameInput) == 0 && *logGroupLikeInput == "") || *logStreamLikeInput == "" || *helpInput {
 		flag.Usage()
 		logger.Fatal("Stopping")
 	}
 
-	cfg, err := external.LoadDefaultAWSConfig(
-		external.WithMFATokenFunc(stscreds.StdinTokenProvider),
-		external.WithSharedConfigProfile(*profileInput),
-		external.WithRegion(*regionInput),
-	)
+	cfg, err := loadAWSConfig(*profileInput, *regionInput, *endpointURLInput, *assumeRoleArnInput, *roleSessionNameInput, *externalIDInput)
 	if err != nil {
 		logger.Fatalf("Failed LoadDefaultAWSConfig: %s", err.Error())
 	}
 
 	if err = checkCredentials(cfg); err != nil {
-		logger.Fatalf(`Ensure that your credential profile %s has been properly configured (refer to https://docs.aws.amazon.com/cli/latest/userguide/cli-multiple-profiles.html).`, *profileInput)
+		logger.Fatalf(`Failed to obtain credentials from profile %q, the EC2/ECS instance role, or --assume-role-arn: %s`, *profileInput, err.Error())
 	}
 
 	logsClient := cloudwatchlogs.New(cfg)
 
-	logStreamMatcher := regexp.MustCompile(strings.Replace(*logStreamLikeInput, "*", ".*", -1))
-
-	stscreds.DefaultDuration = time.Minute * 60
+	rc, err := newRuntimeConfig(logGroupNameInput, *logStreamLikeInput, *filterPatternInput, *outputInput)
+	if err != nil {
+		logger.Fatalf("Invalid --log-stream-like: %s", err.Error())
+	}
 
 	var startTime time.Time // From parameters
 	var endTime time.Time   // From parameters
@@ -147,87 +149,117 @@ func main() {
 		fmt.Printf("Within: Start: %s; End: %s\n\n", s.Format(time.RFC3339Nano), e.Format(time.RFC3339Nano))
 	}
 
-	filterLogEventsInput := cloudwatchlogs.FilterLogEventsInput{LogGroupName: logGroupNameInput, StartTime: &startTimeUnix, EndTime: &endTimeUnix}
-	if len(*filterPatternInput) > 0 {
-		filterLogEventsInput.FilterPattern = filterPatternInput
+	lm, err := newLineMatcher(*datetimeFormatInput, *multilinePatternInput)
+	if err != nil {
+		logger.Fatalf("Invalid --multiline-pattern/--datetime-format: %s", err.Error())
+	}
+
+	fm, err := newFormatter(rc, *templateInput, *colorInput)
+	if err != nil {
+		logger.Fatalf("Invalid output flags: %s", err.Error())
+	}
+
+	checkpoints, err := loadCheckpointStore(*checkpointFileInput)
+	if err != nil {
+		logger.Fatalf("Failed to load --checkpoint-file: %s", err.Error())
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	raw := make(chan tailEvent, 1024)
+	out := make(chan tailEvent, 1024)
+	tm := newTailManager(ctx, logger, logsClient, raw, checkpoints, *maxConcurrentStreamsInput, *pollIntervalInput, *streamInactiveTimeoutInput, lm)
+	go reorderEvents(raw, out, reorderWindow)
+
+	printerDone := make(chan struct{})
+	go func() {
+		defer close(printerDone)
+		for evt := range out {
+			if pattern := rc.FilterPattern(); pattern != "" && !strings.Contains(evt.Message, pattern) {
+				continue
+			}
+			line, err := fm.render(evt)
+			if err != nil {
+				logger.Printf("Error rendering event: %s", err.Error())
+				continue
+			}
+			fmt.Fprintln(fm.writer(), line)
+		}
+	}()
+
 	c := make(chan os.Signal, 100)
-	signal.Notify(c, syscall.SIGUSR1)
+	signal.Notify(c, syscall.SIGUSR1, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
 
-	start := time.Time{}
+	refresh := time.Time{}
+	discovered := false
 	for {
-
 		select {
 		case sig := <-c:
-			if sig == syscall.SIGUSR1 {
+			switch sig {
+			case syscall.SIGUSR1:
 				logger.Println("Restarting")
-				start = time.Time{}
+				refresh = time.Time{}
+				discovered = false
+			case syscall.SIGHUP:
+				if *configInput == "" {
+					logger.Println("SIGHUP received but no --config is set, ignoring")
+					break
+				}
+				if err := rc.reload(*configInput); err != nil {
+					logger.Printf("Error reloading --config: %s", err.Error())
+					break
+				}
+				logger.Println("Reloaded --config")
+				refresh = time.Time{}
+				discovered = false
+			case syscall.SIGINT, syscall.SIGTERM:
+				logger.Println("Shutting down")
+				cancel()
 			}
 		default:
 		}
 
-		if time.Since(start) > (5 * time.Minute) {
-			start = time.Now()
-			filterLogEventsInput.LogStreamNames, err = getLogStreams(logger, logsClient, *logGroupNameInput, logStreamMatcher, startTimeUnix)
-			if err != nil {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if !discovered || (*logStreamRefreshInput && time.Since(refresh) > (5*time.Minute)) {
+			refresh = time.Now()
+			logStreamMatcher := rc.StreamMatcher()
+			logGroupNames, groupsErr := discoverLogGroups(logger, logsClient, rc.LogGroupNames(), *logGroupLikeInput)
+			if groupsErr != nil {
 				time.Sleep(5 * time.Second)
 				continue
 			}
-		}
-		filterLogEventsRequest := logsClient.FilterLogEventsRequest(&filterLogEventsInput)
-		filterLogEventsRequestIter := filterLogEventsRequest.Paginate()
-
-		all := make(chan cloudwatchlogs.FilteredLogEvent)
-		go func(filterLogEventsRequestIter *cloudwatchlogs.FilterLogEventsPager) {
-			defer close(all)
-			for filterLogEventsRequestIter.Next() {
-				filterLogEventsOutput := *filterLogEventsRequestIter.CurrentPage()
-				for _, item := range filterLogEventsOutput.Events {
-					all <- item
-				}
-			}
-		}(&filterLogEventsRequestIter)
-
-		var latest int64
-		var output []cloudwatchlogs.FilteredLogEvent
-		for row := range all {
-			if *row.Timestamp > latest {
-				latest = *row.Timestamp
+			fm.setGroupCount(len(logGroupNames))
+
+			var wg sync.WaitGroup
+			for _, logGroupName := range logGroupNames {
+				wg.Add(1)
+				go func(logGroupName string) {
+					defer wg.Done()
+					logStreamNames, streamsErr := getLogStreams(logger, logsClient, logGroupName, logStreamMatcher, startTimeUnix)
+					if streamsErr != nil {
+						return
+					}
+					tm.sync(logGroupName, logStreamNames, startTimeUnix)
+				}(logGroupName)
 			}
-			output = append(output, row)
-		}
-
-		sort.Sort(msg(output))
-		for _, o := range output {
-			p := strings.Split(*o.LogStreamName, "/")
-			logger.Printf("%-30s  %s\n", strings.Join(p[:1], "/"), *o.Message)
+			wg.Wait()
+			discovered = true
 		}
 
-		err = filterLogEventsRequestIter.Err()
-		if err != nil {
-			logger.Printf("Error paging: %s", err.Error())
-			continue
-		}
-
-		if !endTime.IsZero() && (endTime.UnixNano()/(1000*1000)) <= latest {
-			return
-		}
-
-		if len(output) == 0 {
-			time.Sleep(5 * time.Second)
-			continue
-		}
-		startTimeUnix = latest + 1
-
-		filterLogEventsInput.StartTime = &startTimeUnix
-
-		if !*logStreamRefreshInput {
-			return
+		if !endTime.IsZero() && time.Now().After(endTime) {
+			break
 		}
 
-		filterLogEventsInput.EndTime = &endTimeUnix
+		time.Sleep(time.Second)
 	}
+
+	tm.stopAll()
+	close(raw)
+	<-printerDone
 }
 func toTime(in string, def time.Time) time.Time {
 	t, err := time.Parse(time.RFC3339, in)
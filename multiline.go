@@ -0,0 +1,131 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// multilineFlushTimeout bounds how long a partially-buffered event (e.g. the
+// head of a stack trace) can sit unflushed waiting for its continuation
+// lines, so a stream that goes quiet mid-trace never swallows it.
+const multilineFlushTimeout = 2 * time.Second
+
+// lineMatcher decides, for --multiline-pattern/--datetime-format, whether a
+// freshly received line starts a new logical event or continues the one
+// being buffered. It mirrors the awslogs Docker logging driver: a line
+// starts a new event only when it matches the datetime prefix and does not
+// also match the continuation pattern.
+type lineMatcher struct {
+	datetimeStart *regexp.Regexp
+	continuation  *regexp.Regexp
+}
+
+func newLineMatcher(datetimeFormat, multilinePattern string) (*lineMatcher, error) {
+	if datetimeFormat == "" && multilinePattern == "" {
+		return nil, nil
+	}
+	lm := &lineMatcher{}
+	if datetimeFormat != "" {
+		re, err := datetimeFormatToRegex(datetimeFormat)
+		if err != nil {
+			return nil, err
+		}
+		lm.datetimeStart = re
+	}
+	if multilinePattern != "" {
+		re, err := regexp.Compile(multilinePattern)
+		if err != nil {
+			return nil, err
+		}
+		lm.continuation = re
+	}
+	return lm, nil
+}
+
+// isStart reports whether message begins a new logical event.
+func (lm *lineMatcher) isStart(message string) bool {
+	if lm.continuation != nil && lm.continuation.MatchString(message) {
+		return false
+	}
+	if lm.datetimeStart != nil {
+		return lm.datetimeStart.MatchString(message)
+	}
+	return true
+}
+
+// datetimeFormatToRegex turns a strftime-ish datetime layout (the same
+// tokens accepted by the awslogs Docker driver's awslogs-datetime-format
+// option) into a regex anchored at the start of the line. Unrecognized
+// characters are matched literally.
+func datetimeFormatToRegex(format string) (*regexp.Regexp, error) {
+	replacer := map[string]string{
+		"%Y": `\d{4}`,
+		"%y": `\d{2}`,
+		"%m": `\d{2}`,
+		"%d": `\d{2}`,
+		"%H": `\d{2}`,
+		"%M": `\d{2}`,
+		"%S": `\d{2}`,
+		"%L": `\d{3}`,
+		"%z": `[+-]\d{4}`,
+		"%Z": `[A-Za-z]+`,
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(format); {
+		matched := false
+		for token, pattern := range replacer {
+			if strings.HasPrefix(format[i:], token) {
+				b.WriteString(pattern)
+				i += len(token)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		b.WriteString(regexp.QuoteMeta(string(format[i])))
+		i++
+	}
+	return regexp.Compile(b.String())
+}
+
+// multilineBuffer accumulates continuation lines for a single stream,
+// preserving the earliest timestamp seen as the timestamp of the merged
+// event.
+type multilineBuffer struct {
+	pending    *tailEvent
+	lastAppend time.Time
+}
+
+// append folds evt into the buffer. When evt starts a new logical event it
+// becomes the pending event and the previously buffered one (nil if the
+// buffer was empty) is returned to flush; when evt is a continuation line
+// it is stitched onto the pending event and nil is returned.
+func (b *multilineBuffer) append(evt tailEvent, lm *lineMatcher) *tailEvent {
+	if lm.isStart(evt.Message) || b.pending == nil {
+		flushed := b.pending
+		b.pending = &evt
+		b.lastAppend = time.Now()
+		return flushed
+	}
+	b.pending.Message = b.pending.Message + "\n" + evt.Message
+	if evt.IngestionTime > b.pending.IngestionTime {
+		b.pending.IngestionTime = evt.IngestionTime
+	}
+	b.lastAppend = time.Now()
+	return nil
+}
+
+// flushIfStale returns and clears the pending event once it has sat longer
+// than multilineFlushTimeout without a continuation line arriving.
+func (b *multilineBuffer) flushIfStale() *tailEvent {
+	if b.pending == nil || time.Since(b.lastAppend) < multilineFlushTimeout {
+		return nil
+	}
+	flushed := b.pending
+	b.pending = nil
+	return flushed
+}
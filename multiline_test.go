@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDatetimeFormatToRegex(t *testing.T) {
+	cases := []struct {
+		format     string
+		matches    []string
+		nonMatches []string
+	}{
+		{
+			format:     "%Y-%m-%d %H:%M:%S",
+			matches:    []string{"2026-07-27 10:00:00 some message"},
+			nonMatches: []string{"some message", "26-07-27 10:00:00 some message"},
+		},
+		{
+			format:  "%Y-%m-%dT%H:%M:%S.%L%z",
+			matches: []string{"2026-07-27T10:00:00.123+0000 some message"},
+		},
+	}
+
+	for _, c := range cases {
+		re, err := datetimeFormatToRegex(c.format)
+		if err != nil {
+			t.Fatalf("datetimeFormatToRegex(%q) error: %s", c.format, err)
+		}
+		for _, m := range c.matches {
+			if !re.MatchString(m) {
+				t.Errorf("datetimeFormatToRegex(%q) did not match %q", c.format, m)
+			}
+		}
+		for _, nm := range c.nonMatches {
+			if re.MatchString(nm) {
+				t.Errorf("datetimeFormatToRegex(%q) unexpectedly matched %q", c.format, nm)
+			}
+		}
+	}
+}
+
+func TestMultilineBufferAppend(t *testing.T) {
+	lm, err := newLineMatcher("%Y-%m-%d %H:%M:%S", "")
+	if err != nil {
+		t.Fatalf("newLineMatcher error: %s", err)
+	}
+
+	var buf multilineBuffer
+
+	if flushed := buf.append(tailEvent{Message: "2026-07-27 10:00:00 head", Timestamp: 1, IngestionTime: 1}, lm); flushed != nil {
+		t.Fatalf("first append should not flush anything, got %+v", flushed)
+	}
+
+	if flushed := buf.append(tailEvent{Message: "  continuation line", Timestamp: 2, IngestionTime: 2}, lm); flushed != nil {
+		t.Fatalf("continuation line should be absorbed, not flushed, got %+v", flushed)
+	}
+	if buf.pending.Message != "2026-07-27 10:00:00 head\n  continuation line" {
+		t.Fatalf("continuation line not stitched onto pending, got %q", buf.pending.Message)
+	}
+	if buf.pending.IngestionTime != 2 {
+		t.Fatalf("pending IngestionTime should track the latest line, got %d", buf.pending.IngestionTime)
+	}
+
+	flushed := buf.append(tailEvent{Message: "2026-07-27 10:00:05 next head", Timestamp: 3, IngestionTime: 3}, lm)
+	if flushed == nil {
+		t.Fatal("a new head line should flush the previously pending event")
+	}
+	if flushed.Timestamp != 1 {
+		t.Fatalf("flushed event should keep the earliest timestamp, got %d", flushed.Timestamp)
+	}
+	if buf.pending.Message != "2026-07-27 10:00:05 next head" {
+		t.Fatalf("new head line should become the pending event, got %q", buf.pending.Message)
+	}
+}
+
+func TestMultilineBufferFlushIfStale(t *testing.T) {
+	var buf multilineBuffer
+
+	if flushed := buf.flushIfStale(); flushed != nil {
+		t.Fatalf("empty buffer should never flush, got %+v", flushed)
+	}
+
+	evt := tailEvent{Message: "head", Timestamp: 1}
+	buf.pending = &evt
+	buf.lastAppend = time.Now()
+
+	if flushed := buf.flushIfStale(); flushed != nil {
+		t.Fatalf("freshly appended event should not flush yet, got %+v", flushed)
+	}
+
+	buf.lastAppend = time.Now().Add(-multilineFlushTimeout - time.Millisecond)
+	flushed := buf.flushIfStale()
+	if flushed == nil || flushed.Message != "head" {
+		t.Fatalf("stale event should flush, got %+v", flushed)
+	}
+	if buf.pending != nil {
+		t.Fatal("flushIfStale should clear pending once flushed")
+	}
+}
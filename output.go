@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/template"
+)
+
+const (
+	outputText     = "text"
+	outputJSON     = "json"
+	outputLogfmt   = "logfmt"
+	outputTemplate = "template"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+const (
+	ansiCyan   = "\x1b[36m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+// eventView is the shape exposed to --template and embedded verbatim in
+// --output json. When the message body itself is a JSON object, its fields
+// are also exposed, nested under Fields.
+type eventView struct {
+	Timestamp     int64
+	IngestionTime int64
+	LogGroupName  string
+	LogStreamName string
+	Message       string
+	EventID       string
+	Fields        map[string]interface{} `json:"Fields,omitempty"`
+}
+
+func newEventView(evt tailEvent) eventView {
+	v := eventView{
+		Timestamp:     evt.Timestamp,
+		IngestionTime: evt.IngestionTime,
+		LogGroupName:  evt.LogGroupName,
+		LogStreamName: evt.LogStreamName,
+		Message:       evt.Message,
+		EventID:       evt.EventID,
+	}
+	var fields map[string]interface{}
+	if json.Unmarshal([]byte(evt.Message), &fields) == nil {
+		v.Fields = fields
+	}
+	return v
+}
+
+// formatter renders tailEvents for one --output mode and writes them to the
+// stream appropriate for that mode (status/logging always stays on stderr;
+// only the event payload itself moves to stdout for the machine-readable
+// formats).
+type formatter struct {
+	rc    *runtimeConfig
+	tmpl  *template.Template
+	color bool
+
+	// groupCount is updated after each log-group discovery refresh; once
+	// more than one group is active the text layout grows a group column.
+	groupCount int32
+}
+
+func (f *formatter) setGroupCount(n int) {
+	atomic.StoreInt32(&f.groupCount, int32(n))
+}
+
+func newFormatter(rc *runtimeConfig, templateSrc, color string) (*formatter, error) {
+	f := &formatter{rc: rc}
+
+	switch rc.Output() {
+	case outputJSON, outputLogfmt, outputTemplate, "", outputText:
+	default:
+		return nil, fmt.Errorf("unknown --output %q", rc.Output())
+	}
+
+	if templateSrc != "" {
+		t, err := template.New("event").Parse(templateSrc)
+		if err != nil {
+			return nil, fmt.Errorf("parsing --template: %w", err)
+		}
+		f.tmpl = t
+	} else if rc.Output() == outputTemplate {
+		return nil, fmt.Errorf("--template is required when --output=template")
+	}
+
+	switch color {
+	case colorAlways:
+		f.color = true
+	case colorNever, "":
+		f.color = false
+	case colorAuto:
+		f.color = isTerminal(f.writer())
+	default:
+		return nil, fmt.Errorf("unknown --color %q", color)
+	}
+
+	return f, nil
+}
+
+// writer returns the stream the current --output mode writes event
+// payloads to: stdout for the machine-readable formats (so `| jq` works
+// cleanly), stderr for text (matching this tool's historical behavior of
+// treating printed lines as status output).
+func (f *formatter) writer() io.Writer {
+	switch f.rc.Output() {
+	case outputJSON, outputLogfmt, outputTemplate:
+		return os.Stdout
+	default:
+		return os.Stderr
+	}
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// render formats evt as a single line in the formatter's configured
+// --output mode, ready to be written to f.writer().
+func (f *formatter) render(evt tailEvent) (string, error) {
+	view := newEventView(evt)
+
+	switch f.rc.Output() {
+	case outputJSON:
+		b, err := json.Marshal(view)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+
+	case outputLogfmt:
+		return f.renderLogfmt(view), nil
+
+	case outputTemplate:
+		if f.tmpl == nil {
+			return "", fmt.Errorf("--output=template requires --template")
+		}
+		var buf bytes.Buffer
+		if err := f.tmpl.Execute(&buf, view); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+
+	default: // outputText
+		stream := strings.Join(strings.SplitN(evt.LogStreamName, "/", 2)[:1], "/")
+		message := evt.Message
+		if f.color {
+			stream = ansiCyan + stream + ansiReset
+			message = f.highlightFilterMatches(message)
+		}
+		if atomic.LoadInt32(&f.groupCount) > 1 {
+			return fmt.Sprintf("%-20s  %-30s  %s", evt.LogGroupName, stream, message), nil
+		}
+		return fmt.Sprintf("%-30s  %s", stream, message), nil
+	}
+}
+
+func (f *formatter) highlightFilterMatches(message string) string {
+	pattern := f.rc.FilterPattern()
+	if pattern == "" {
+		return message
+	}
+	return strings.ReplaceAll(message, pattern, ansiYellow+pattern+ansiReset)
+}
+
+func (f *formatter) renderLogfmt(v eventView) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "timestamp=%d ingestion_time=%d log_group=%s log_stream=%s", v.Timestamp, v.IngestionTime, logfmtQuote(v.LogGroupName), logfmtQuote(v.LogStreamName))
+	if v.EventID != "" {
+		fmt.Fprintf(&b, " event_id=%s", logfmtQuote(v.EventID))
+	}
+	fmt.Fprintf(&b, " message=%s", logfmtQuote(v.Message))
+	return b.String()
+}
+
+func logfmtQuote(s string) string {
+	if s == "" {
+		return `""`
+	}
+	if !strings.ContainsAny(s, " =\"\n\t") {
+		return s
+	}
+	return strconv.Quote(s)
+}
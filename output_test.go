@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestLogfmtQuote(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: `""`},
+		{in: "plain", want: "plain"},
+		{in: "has space", want: `"has space"`},
+		{in: `has"quote`, want: `"has\"quote"`},
+		{in: "has=equals", want: `"has=equals"`},
+		{in: "has\ttab", want: "\"has\\ttab\""},
+		{in: "has\nnewline", want: "\"has\\nnewline\""},
+	}
+
+	for _, c := range cases {
+		if got := logfmtQuote(c.in); got != c.want {
+			t.Errorf("logfmtQuote(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
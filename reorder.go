@@ -0,0 +1,53 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// reorderWindow bounds how long the printer waits before emitting received
+// events, so it can sort by timestamp across streams. Each streamTailer
+// polls independently, so events from different streams arrive in
+// whatever order their goroutine happened to get scheduled, not in
+// chronological order; without this, interleaving would only be correct
+// per-stream, not across a log group's streams.
+const reorderWindow = 2 * time.Second
+
+// reorderEvents reads tailEvents from in, batches them for reorderWindow,
+// and writes each batch to out sorted by Timestamp before starting the
+// next batch. It closes out once in is closed, after flushing whatever was
+// still buffered. This bounds cross-stream ordering to "correct within
+// reorderWindow of arrival," not a global guarantee: a straggler event
+// that arrives more than reorderWindow late still prints out of order.
+func reorderEvents(in <-chan tailEvent, out chan<- tailEvent, window time.Duration) {
+	defer close(out)
+
+	var batch []tailEvent
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		sort.SliceStable(batch, func(i, j int) bool { return batch[i].Timestamp < batch[j].Timestamp })
+		for _, evt := range batch {
+			out <- evt
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case evt, ok := <-in:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, evt)
+		case <-timer.C:
+			flush()
+			timer.Reset(window)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+)
+
+// tailEvent is the common shape printed for every log line, regardless of
+// which CloudWatch API call produced it.
+type tailEvent struct {
+	LogGroupName  string
+	LogStreamName string
+	Timestamp     int64
+	IngestionTime int64
+	Message       string
+	EventID       string
+}
+
+// eventID synthesizes a stable identifier for an OutputLogEvent, which
+// GetLogEvents does not assign one of its own. It is derived entirely from
+// the event's own content (stream, timestamp, ingestion time, and a hash
+// of the message) rather than its position in a response page, so the
+// same event refetched in a differently-paged request — as happens on a
+// checkpoint resume — still produces the same ID.
+func eventID(logStreamName string, timestamp, ingestionTime int64, message string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(message))
+	return fmt.Sprintf("%s:%d:%d:%x", logStreamName, timestamp, ingestionTime, h.Sum64())
+}
+
+// streamTailer owns the polling loop for a single log stream. It is torn
+// down once the stream has gone idle for longer than inactiveTimeout.
+type streamTailer struct {
+	logGroupName  string
+	logStreamName string
+
+	nextForwardToken *string
+	lastEventAt      time.Time
+
+	// lastForwarded is the most recent event this tailer has handed to
+	// forward(), used to pair a page-boundary checkpoint (see
+	// checkpointPageBoundary) with the position it actually corresponds
+	// to.
+	lastForwarded *tailEvent
+
+	// resumeAfterTimestamp/resumeAfterEventID are the checkpointed
+	// high-water mark used when the checkpoint has no nextForwardToken:
+	// GetLogEvents then starts from resumeAfterTimestamp instead of
+	// startTimeUnix, so only events since the checkpoint are fetched
+	// again. Set from the checkpoint in sync() and
+	// consulted by shouldSkipForResume until pastResume flips true.
+	resumeAfterTimestamp int64
+	resumeAfterEventID   string
+	pastResume           bool
+
+	pollInterval    time.Duration
+	inactiveTimeout time.Duration
+
+	buffer multilineBuffer
+
+	stop chan struct{}
+}
+
+// tailManager fans a log group's streams out across one goroutine per
+// stream, bounded by a semaphore so a log group with thousands of streams
+// doesn't open thousands of concurrent GetLogEvents pollers.
+type tailManager struct {
+	ctx        context.Context
+	logsClient *cloudwatchlogs.CloudWatchLogs
+	logger     *log.Logger
+	out        chan<- tailEvent
+	checkpoint *checkpointStore
+
+	pollInterval    time.Duration
+	inactiveTimeout time.Duration
+	lineMatcher     *lineMatcher
+
+	sem chan struct{}
+
+	mu      sync.Mutex
+	tailers map[string]*streamTailer // keyed by logGroupName + "\x00" + logStreamName
+	wg      sync.WaitGroup
+}
+
+func newTailManager(ctx context.Context, logger *log.Logger, logsClient *cloudwatchlogs.CloudWatchLogs, out chan<- tailEvent, checkpoint *checkpointStore, maxConcurrentStreams int, pollInterval, inactiveTimeout time.Duration, lm *lineMatcher) *tailManager {
+	return &tailManager{
+		ctx:             ctx,
+		logsClient:      logsClient,
+		logger:          logger,
+		out:             out,
+		checkpoint:      checkpoint,
+		pollInterval:    pollInterval,
+		inactiveTimeout: inactiveTimeout,
+		lineMatcher:     lm,
+		sem:             make(chan struct{}, maxConcurrentStreams),
+		tailers:         make(map[string]*streamTailer),
+	}
+}
+
+func tailerKey(logGroupName, logStreamName string) string {
+	return logGroupName + "\x00" + logStreamName
+}
+
+// sync reconciles the manager's running tailers against the currently
+// discovered set of streams for a log group: new streams get a goroutine,
+// already-tailed streams are left untouched.
+func (m *tailManager) sync(logGroupName string, logStreamNames []string, startTimeUnix int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, name := range logStreamNames {
+		key := tailerKey(logGroupName, name)
+		if _, ok := m.tailers[key]; ok {
+			continue
+		}
+		t := &streamTailer{
+			logGroupName:    logGroupName,
+			logStreamName:   name,
+			pollInterval:    m.pollInterval,
+			inactiveTimeout: m.inactiveTimeout,
+			stop:            make(chan struct{}),
+		}
+		if cp, ok := m.checkpoint.get(logGroupName, name); ok {
+			if cp.NextForwardToken != "" {
+				t.nextForwardToken = &cp.NextForwardToken
+			} else if cp.Timestamp > 0 {
+				t.resumeAfterTimestamp = cp.Timestamp
+				t.resumeAfterEventID = cp.EventID
+			}
+		}
+		m.tailers[key] = t
+		m.wg.Add(1)
+		go m.run(t, startTimeUnix)
+	}
+}
+
+func (m *tailManager) run(t *streamTailer, startTimeUnix int64) {
+	defer m.wg.Done()
+	defer func() {
+		m.mu.Lock()
+		delete(m.tailers, tailerKey(t.logGroupName, t.logStreamName))
+		m.mu.Unlock()
+	}()
+
+	// Hold a semaphore slot for this tailer's entire lifetime, not just
+	// around individual Send calls: that's what actually caps the number
+	// of streams being tailed at once. A stream admitted past the limit
+	// just blocks here until an active tailer exits, rather than every
+	// discovered stream spinning up its own live poller.
+	select {
+	case <-t.stop:
+		return
+	case <-m.ctx.Done():
+		return
+	case m.sem <- struct{}{}:
+	}
+	defer func() { <-m.sem }()
+
+	// Only start the idle clock once this tailer has actually been
+	// admitted: a stream queued behind --max-concurrent-streams can wait
+	// longer than --stream-inactive-timeout for a slot, and stamping
+	// lastEventAt at creation would make its very first poll look
+	// instantly idle and tear it down before it ever ran.
+	t.lastEventAt = time.Now()
+
+	startFromHead := true
+	effectiveStartTime := startTimeUnix
+	if t.resumeAfterTimestamp > 0 {
+		effectiveStartTime = t.resumeAfterTimestamp
+	}
+	getLogEventsInput := cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  &t.logGroupName,
+		LogStreamName: &t.logStreamName,
+		StartTime:     &effectiveStartTime,
+		StartFromHead: &startFromHead,
+	}
+
+	for {
+		getLogEventsInput.NextToken = t.nextForwardToken
+		req := m.logsClient.GetLogEventsRequest(&getLogEventsInput)
+		resp, err := req.Send()
+
+		if err != nil {
+			m.logger.Printf("Error tailing %s/%s: %s", t.logGroupName, t.logStreamName, err.Error())
+			select {
+			case <-t.stop:
+				return
+			case <-m.ctx.Done():
+				return
+			case <-time.After(t.pollInterval):
+			}
+			continue
+		}
+
+		if len(resp.Events) > 0 {
+			t.lastEventAt = time.Now()
+			for _, e := range resp.Events {
+				evt := tailEvent{
+					LogGroupName:  t.logGroupName,
+					LogStreamName: t.logStreamName,
+					Timestamp:     *e.Timestamp,
+					IngestionTime: *e.IngestionTime,
+					Message:       *e.Message,
+					EventID:       eventID(t.logStreamName, *e.Timestamp, *e.IngestionTime, *e.Message),
+				}
+				if t.shouldSkipForResume(evt) {
+					continue
+				}
+				m.emit(t, evt)
+			}
+		}
+
+		// GetLogEvents returns the same token back once a stream is caught
+		// up; only advance (and checkpoint) when it moves. Idleness is judged
+		// by lastEventAt, which only new events refresh.
+		if resp.NextForwardToken != nil && (t.nextForwardToken == nil || *resp.NextForwardToken != *t.nextForwardToken) {
+			t.nextForwardToken = resp.NextForwardToken
+			m.checkpointPageBoundary(t)
+		}
+
+		if flushed := t.buffer.flushIfStale(); flushed != nil {
+			m.forward(t, *flushed)
+		}
+
+		if time.Since(t.lastEventAt) > t.inactiveTimeout {
+			if flushed := t.buffer.pending; flushed != nil {
+				m.forward(t, *flushed)
+				t.buffer.pending = nil
+			}
+			m.logger.Printf("Stream %s/%s idle for over %s, stopping tailer", t.logGroupName, t.logStreamName, t.inactiveTimeout)
+			return
+		}
+
+		select {
+		case <-t.stop:
+			return
+		case <-m.ctx.Done():
+			return
+		case <-time.After(t.pollInterval):
+		}
+	}
+}
+
+// shouldSkipForResume reports whether evt was already emitted by a prior
+// run and should be dropped. It only applies while resuming from a
+// checkpoint that had no NextForwardToken (its last write came from
+// forward, which records no token): GetLogEvents is restarted from
+// resumeAfterTimestamp, which would otherwise re-emit every event up to and
+// including the last one this tailer already printed.
+func (t *streamTailer) shouldSkipForResume(evt tailEvent) bool {
+	if t.pastResume || t.resumeAfterTimestamp == 0 {
+		return false
+	}
+	if evt.Timestamp < t.resumeAfterTimestamp {
+		return true
+	}
+	if evt.Timestamp == t.resumeAfterTimestamp && evt.EventID != t.resumeAfterEventID {
+		return true
+	}
+	// Either strictly newer than the checkpoint, or the exact event last
+	// seen there (which we still skip, once, since it was already
+	// emitted): everything from here on is new.
+	matchesCheckpointedEvent := evt.Timestamp == t.resumeAfterTimestamp
+	t.pastResume = true
+	return matchesCheckpointedEvent
+}
+
+// emit hands evt to the stream's multiline buffer (when multiline stitching
+// is enabled) and forwards whatever the buffer decides is ready to print.
+func (m *tailManager) emit(t *streamTailer, evt tailEvent) {
+	if m.lineMatcher == nil {
+		m.forward(t, evt)
+		return
+	}
+	if flushed := t.buffer.append(evt, m.lineMatcher); flushed != nil {
+		m.forward(t, *flushed)
+	}
+}
+
+// forward prints evt and, when --checkpoint-file is set, records it as the
+// resume point for this stream so a restart picks up from here instead of
+// double-printing or losing events.
+//
+// It deliberately never persists t.nextForwardToken: that token was used
+// to fetch the page evt came from and only advances once the whole page
+// has been processed (see the run loop), so pairing it with a per-event
+// checkpoint would have a restart re-fetch the current page and re-emit
+// the events of it that were already printed. A resume instead falls back to the Timestamp/EventID
+// high-water mark until the next page boundary re-establishes a token
+// (checkpointPageBoundary).
+func (m *tailManager) forward(t *streamTailer, evt tailEvent) {
+	m.out <- evt
+	t.lastForwarded = &evt
+
+	if err := m.checkpoint.save(checkpoint{
+		LogGroupName:  evt.LogGroupName,
+		LogStreamName: evt.LogStreamName,
+		Timestamp:     evt.Timestamp,
+		EventID:       evt.EventID,
+	}); err != nil {
+		m.logger.Printf("Error saving checkpoint for %s/%s: %s", t.logGroupName, t.logStreamName, err.Error())
+	}
+}
+
+// checkpointPageBoundary records the forward token now that it has
+// advanced past the page just processed, together with the last event
+// this tailer emitted. A resume from this checkpoint can jump straight to
+// the next page via the token instead of re-fetching this one.
+func (m *tailManager) checkpointPageBoundary(t *streamTailer) {
+	cp := checkpoint{
+		LogGroupName:     t.logGroupName,
+		LogStreamName:    t.logStreamName,
+		NextForwardToken: *t.nextForwardToken,
+	}
+	if t.lastForwarded != nil {
+		cp.Timestamp = t.lastForwarded.Timestamp
+		cp.EventID = t.lastForwarded.EventID
+	}
+	if err := m.checkpoint.save(cp); err != nil {
+		m.logger.Printf("Error saving checkpoint for %s/%s: %s", t.logGroupName, t.logStreamName, err.Error())
+	}
+}
+
+// stopAll signals every running tailer and blocks until they've exited.
+func (m *tailManager) stopAll() {
+	m.mu.Lock()
+	for _, t := range m.tailers {
+		close(t.stop)
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+}